@@ -0,0 +1,157 @@
+package base
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const CursorQuery = "cursor"
+
+const (
+	cursorTiebreakColumn    = "id"
+	cursorTiebreakSQLColumn = "cve.id"
+	cursorDirectionPrev     = "prev"
+)
+
+// ErrOffsetCursorConflict is returned when a caller requests both offset
+// and cursor pagination on the same query
+var ErrOffsetCursorConflict = errors.New("offset and cursor pagination are mutually exclusive")
+
+// CursorKey is one column of the tie-broken sort key a cursor was built
+// from, in the same order the query is sorted by
+type CursorKey struct {
+	Column string      `json:"column"`
+	Value  interface{} `json:"value"`
+	Desc   bool        `json:"desc"`
+}
+
+// CursorPayload is the base64url-encoded JSON carried by the `cursor`
+// query parameter. Keys always ends with the cursorTiebreakColumn entry,
+// so paging stays deterministic even when the user's sort isn't unique.
+type CursorPayload struct {
+	Keys      []CursorKey `json:"keys"`
+	Direction string      `json:"direction,omitempty"`
+}
+
+// DecodeCursor decodes a `cursor` query value into its payload
+func DecodeCursor(raw string) (*CursorPayload, error) {
+	data, err := base64.URLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	var payload CursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if len(payload.Keys) == 0 || payload.Keys[len(payload.Keys)-1].Column != cursorTiebreakColumn {
+		return nil, errors.New("cursor is missing its tiebreaker column")
+	}
+	return &payload, nil
+}
+
+// EncodeCursor builds an opaque cursor value from the tie-broken sort key
+// of a result row. Controllers use this to build next_cursor/prev_cursor.
+func EncodeCursor(keys []CursorKey, direction string) string {
+	data, _ := json.Marshal(CursorPayload{Keys: keys, Direction: direction})
+	return base64.URLEncoding.EncodeToString(data)
+}
+
+// Cursor replaces Offset with keyset pagination: instead of skipping rows
+// server-side, it filters to rows strictly after (or before) the last seen
+// sort key, which PostgreSQL can satisfy with the existing sort indexes
+// regardless of how deep the caller pages.
+//
+// For a "prev" cursor, rows are fetched ordered towards the cursor (so
+// LIMIT keeps the page immediately preceding it) rather than away from it -
+// that means the rows come back in the reverse of the client-facing order.
+// Controllers must reverse the returned slice before building prev_cursor
+// and sending the page to the client.
+// ex. cursor=eyJrZXlzIjpbeyJjb2x1bW4iOiJjdnNzX3Njb3JlIi4uLn1dfQ==
+type Cursor struct {
+	RawFilter
+	Payload CursorPayload
+}
+
+// ApplyQuery builds the lexicographic (col1, col2, ..., id) > (v1, v2, ..., id0)
+// predicate matching the active sort order, expanded into the correct
+// ASC/DESC-aware disjunction, and (re)builds the ORDER BY clause itself so
+// a "prev" cursor walks backwards regardless of what Sort already applied.
+// Controllers must list CursorQuery after SortQuery in allowedFilters (or
+// omit Sort entirely for cursor-paginated endpoints), since ApplyFilters
+// runs filters in that order and a Sort applied after Cursor would append
+// its own ORDER BY back on top of the one Cursor just rebuilt
+func (c *Cursor) ApplyQuery(tx *gorm.DB, args map[string]interface{}) error {
+	sortArgs, ok := args[SortFilterArgs].(SortArgs)
+	if !ok {
+		return errors.New("cursor pagination requires sort filter arguments")
+	}
+
+	forward := c.Payload.Direction != cursorDirectionPrev
+
+	sqlColumn := func(column string) (string, error) {
+		if column == cursorTiebreakColumn {
+			return cursorTiebreakSQLColumn, nil
+		}
+		col, exists := sortArgs.SortableColumns[column]
+		if !exists {
+			return "", fmt.Errorf("invalid cursor column %q", column)
+		}
+		return col, nil
+	}
+
+	var expr strings.Builder
+	var vals []interface{}
+	orderColumns := make([]clause.OrderByColumn, 0, len(c.Payload.Keys))
+	for i, key := range c.Payload.Keys {
+		col, err := sqlColumn(key.Column)
+		if err != nil {
+			return err
+		}
+
+		// walking "prev" reverses the direction of every sort key
+		desc := key.Desc
+		if !forward {
+			desc = !desc
+		}
+		op := ">"
+		if desc {
+			op = "<"
+		}
+
+		if i > 0 {
+			expr.WriteString(" OR ")
+		}
+		expr.WriteString("(")
+		for j := 0; j < i; j++ {
+			prevCol, err := sqlColumn(c.Payload.Keys[j].Column)
+			if err != nil {
+				return err
+			}
+			expr.WriteString(fmt.Sprintf("%s = ? AND ", prevCol))
+			vals = append(vals, c.Payload.Keys[j].Value)
+		}
+		expr.WriteString(fmt.Sprintf("%s %s ?)", col, op))
+		vals = append(vals, key.Value)
+
+		direction := "ASC"
+		if desc {
+			direction = "DESC"
+		}
+		orderColumns = append(orderColumns, clause.OrderByColumn{
+			// Raw: the ASC/DESC and NULLS LAST are baked into Name so they
+			// don't need a separate (unsupported) NULLS LAST field
+			Column:  clause.Column{Name: fmt.Sprintf("%s %s NULLS LAST", col, direction), Raw: true},
+			Reorder: i == 0, // discard whatever ORDER BY Sort already attached
+		})
+	}
+
+	tx.Where(expr.String(), vals...)
+	tx.Clauses(clause.OrderBy{Columns: orderColumns})
+	return nil
+}
@@ -0,0 +1,188 @@
+package base
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+const FilterQuery = "filter"
+
+// FilterConnective is the boolean operator joining a FilterGroup's children
+type FilterConnective string
+
+const (
+	FilterAnd FilterConnective = "and"
+	FilterOr  FilterConnective = "or"
+	FilterNot FilterConnective = "not"
+)
+
+// FilterGroup implements Filter and composes child filters with a boolean
+// connective, so callers can express OR/NOT trees instead of the implicit
+// AND chain ApplyFilters normally builds. The flat query-param style keeps
+// working unchanged, since it is just parsed as an implicit top-level AND.
+type FilterGroup struct {
+	RawFilter
+	Connective FilterConnective
+	Children   []Filter
+}
+
+// ApplyQuery attaches the group's children to tx, combined by Connective.
+// Every child filter is unaware it is part of a group - it still receives
+// the plain *gorm.DB scope it would otherwise attach to.
+func (g *FilterGroup) ApplyQuery(tx *gorm.DB, args map[string]interface{}) error {
+	switch g.Connective {
+	case FilterNot:
+		if len(g.Children) != 1 {
+			return errors.New("filter group: NOT takes exactly one child")
+		}
+		if err := rejectGroupedThreshold(g.Children[0], "NOT"); err != nil {
+			return err
+		}
+		sub, err := g.childScope(tx, g.Children[0], args)
+		if err != nil {
+			return err
+		}
+		tx.Not(sub)
+	case FilterOr:
+		if len(g.Children) == 0 {
+			return nil
+		}
+		expr := tx.Session(&gorm.Session{})
+		for i, child := range g.Children {
+			if err := rejectGroupedThreshold(child, "OR"); err != nil {
+				return err
+			}
+			sub, err := g.childScope(tx, child, args)
+			if err != nil {
+				return err
+			}
+			if i == 0 {
+				expr = expr.Where(sub)
+			} else {
+				expr = expr.Or(sub)
+			}
+		}
+		tx.Where(expr)
+	default: // FilterAnd, including the empty/unset zero value
+		for _, child := range g.Children {
+			if err := child.ApplyQuery(tx, args); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rejectGroupedThreshold rejects an AffectingClusters/AffectingImages
+// threshold nested inside an OR/NOT group. childScope runs each child
+// against its own fresh *gorm.DB (so Or/Not can merge it back in), which
+// means the threshold never sees the outer query's GROUP BY clause and
+// always falls back to its scalar-subquery form instead of HAVING - and
+// even when it did attach a HAVING, gorm's Where/Not only pull a *gorm.DB
+// argument's WHERE clause into the merged condition, silently dropping
+// anything HAVING-based. Rather than ship a threshold filter that quietly
+// behaves differently depending on composition, reject it outright here.
+func rejectGroupedThreshold(f Filter, connective string) error {
+	var hasThreshold bool
+	switch v := f.(type) {
+	case *AffectingClusters:
+		hasThreshold = v.Threshold != nil
+	case *AffectingImages:
+		hasThreshold = v.Threshold != nil
+	case *FilterGroup:
+		for _, child := range v.Children {
+			if err := rejectGroupedThreshold(child, connective); err != nil {
+				return err
+			}
+		}
+	}
+	if hasThreshold {
+		return fmt.Errorf("filter group: a minimum-count threshold filter cannot be composed inside %s", connective)
+	}
+	return nil
+}
+
+// childScope builds an isolated *gorm.DB carrying just f's conditions, so
+// it can be combined with Or/Not without leaking into the parent tx. Filters
+// apply their conditions by mutating the *gorm.DB they are given in place,
+// so the scope must start from its own statement (table preserved) rather
+// than a bare session clone.
+func (g *FilterGroup) childScope(tx *gorm.DB, f Filter, args map[string]interface{}) (*gorm.DB, error) {
+	scope := tx.Session(&gorm.Session{NewDB: true}).Table(tx.Statement.Table)
+	if err := f.ApplyQuery(scope, args); err != nil {
+		return nil, err
+	}
+	return scope, nil
+}
+
+// FilterConstructor builds a concrete Filter from its raw string values,
+// so ParseFilterTree can stay agnostic of individual filter types. The
+// filters middleware registers one constructor per allowed field name,
+// the same values it already uses to build the flat query-param filters.
+type FilterConstructor func(values []string) (Filter, error)
+
+// ParseFilterTree parses a `filter` query param JSON tree such as
+// {"or":[{"severity":["critical"]},{"and":[{"cvss_score":["7.0","10.0"]}]}]}
+// into a FilterGroup, looking up each leaf field's constructor from the
+// controller-provided registry. Unknown fields are rejected.
+func ParseFilterTree(raw json.RawMessage, constructors map[string]FilterConstructor) (*FilterGroup, error) {
+	var node map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &node); err != nil {
+		return nil, fmt.Errorf("invalid filter tree: %w", err)
+	}
+
+	if children, exists := node[string(FilterOr)]; exists {
+		return parseFilterList(children, FilterOr, constructors)
+	}
+	if children, exists := node[string(FilterAnd)]; exists {
+		return parseFilterList(children, FilterAnd, constructors)
+	}
+	if child, exists := node[string(FilterNot)]; exists {
+		sub, err := ParseFilterTree(child, constructors)
+		if err != nil {
+			return nil, err
+		}
+		return &FilterGroup{Connective: FilterNot, Children: []Filter{sub}}, nil
+	}
+
+	// Leaf node: one or more "field": [values] entries, implicitly ANDed
+	group := &FilterGroup{Connective: FilterAnd}
+	for field, rawValues := range node {
+		constructor, exists := constructors[field]
+		if !exists {
+			return nil, fmt.Errorf("unknown filter field %q", field)
+		}
+
+		var values []string
+		if err := json.Unmarshal(rawValues, &values); err != nil {
+			return nil, fmt.Errorf("invalid values for filter field %q: %w", field, err)
+		}
+
+		filter, err := constructor(values)
+		if err != nil {
+			return nil, err
+		}
+		group.Children = append(group.Children, filter)
+	}
+	return group, nil
+}
+
+func parseFilterList(raw json.RawMessage, connective FilterConnective, constructors map[string]FilterConstructor) (*FilterGroup, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("invalid %s filter list: %w", connective, err)
+	}
+
+	group := &FilterGroup{Connective: connective}
+	for _, item := range items {
+		child, err := ParseFilterTree(item, constructors)
+		if err != nil {
+			return nil, err
+		}
+		group.Children = append(group.Children, child)
+	}
+	return group, nil
+}
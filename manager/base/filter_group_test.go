@@ -0,0 +1,136 @@
+package base
+
+import (
+	"encoding/json"
+	"testing"
+
+	"app/base/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterGroupOr(t *testing.T) {
+	tx := newDryRunDB(t)
+	group := FilterGroup{
+		Connective: FilterOr,
+		Children: []Filter{
+			&Severity{Value: []models.Severity{models.Severity("critical")}},
+			&CvssScore{From: 7.0, To: 10.0},
+		},
+	}
+
+	require.NoError(t, group.ApplyQuery(tx, map[string]interface{}{}))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.Contains(t, sql, "cve.severity IN")
+	assert.Contains(t, sql, "OR")
+	assert.Contains(t, sql, "COALESCE(cve.cvss3_score, cve.cvss2_score) >=")
+}
+
+func TestFilterGroupNot(t *testing.T) {
+	tx := newDryRunDB(t)
+	group := FilterGroup{
+		Connective: FilterNot,
+		Children:   []Filter{&Severity{Value: []models.Severity{models.Severity("low")}}},
+	}
+
+	require.NoError(t, group.ApplyQuery(tx, map[string]interface{}{}))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.Contains(t, sql, "NOT")
+	assert.Contains(t, sql, "cve.severity IN")
+}
+
+func TestFilterGroupOrRejectsGroupedThreshold(t *testing.T) {
+	tx := newDryRunDB(t)
+	group := FilterGroup{
+		Connective: FilterOr,
+		Children: []Filter{
+			&Severity{Value: []models.Severity{models.Severity("critical")}},
+			&AffectingClusters{Threshold: &NumericThreshold{Op: ThresholdGte, Value: 5}},
+		},
+	}
+
+	err := group.ApplyQuery(tx, map[string]interface{}{})
+	assert.Error(t, err)
+}
+
+func TestFilterGroupNotRejectsGroupedThreshold(t *testing.T) {
+	tx := newDryRunDB(t)
+	group := FilterGroup{
+		Connective: FilterNot,
+		Children:   []Filter{&AffectingImages{Threshold: &NumericThreshold{Op: ThresholdGte, Value: 5}}},
+	}
+
+	err := group.ApplyQuery(tx, map[string]interface{}{})
+	assert.Error(t, err)
+}
+
+func TestFilterGroupOrAllowsNonThresholdAffectingClusters(t *testing.T) {
+	tx := newDryRunDB(t)
+	group := FilterGroup{
+		Connective: FilterOr,
+		Children: []Filter{
+			&Severity{Value: []models.Severity{models.Severity("critical")}},
+			&AffectingClusters{OneOrMore: true},
+		},
+	}
+
+	require.NoError(t, group.ApplyQuery(tx, map[string]interface{}{}))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+	assert.Contains(t, sql, "EXISTS")
+}
+
+func TestFilterGroupNotRequiresSingleChild(t *testing.T) {
+	tx := newDryRunDB(t)
+	group := FilterGroup{Connective: FilterNot}
+
+	err := group.ApplyQuery(tx, map[string]interface{}{})
+	assert.Error(t, err)
+}
+
+func severityConstructor(values []string) (Filter, error) {
+	severities := make([]models.Severity, 0, len(values))
+	for _, v := range values {
+		severities = append(severities, models.Severity(v))
+	}
+	return &Severity{Value: severities}, nil
+}
+
+func cvssScoreConstructor(values []string) (Filter, error) {
+	return &CvssScore{From: 7.0, To: 10.0}, nil
+}
+
+func TestParseFilterTreeOrAnd(t *testing.T) {
+	tree := json.RawMessage(`{"or":[{"severity":["critical"]},{"and":[{"cvss_score":["7.0","10.0"]}]}]}`)
+	constructors := map[string]FilterConstructor{
+		"severity":   severityConstructor,
+		"cvss_score": cvssScoreConstructor,
+	}
+
+	group, err := ParseFilterTree(tree, constructors)
+	require.NoError(t, err)
+	assert.Equal(t, FilterOr, group.Connective)
+	require.Len(t, group.Children, 2)
+
+	nested, ok := group.Children[1].(*FilterGroup)
+	require.True(t, ok)
+	assert.Equal(t, FilterAnd, nested.Connective)
+}
+
+func TestParseFilterTreeNot(t *testing.T) {
+	tree := json.RawMessage(`{"not":{"severity":["low"]}}`)
+	constructors := map[string]FilterConstructor{"severity": severityConstructor}
+
+	group, err := ParseFilterTree(tree, constructors)
+	require.NoError(t, err)
+	assert.Equal(t, FilterNot, group.Connective)
+	require.Len(t, group.Children, 1)
+}
+
+func TestParseFilterTreeRejectsUnknownField(t *testing.T) {
+	tree := json.RawMessage(`{"nonexistent":["x"]}`)
+	_, err := ParseFilterTree(tree, map[string]FilterConstructor{})
+	assert.Error(t, err)
+}
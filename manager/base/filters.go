@@ -3,6 +3,8 @@ package base
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -66,28 +68,81 @@ func (b *RawFilter) RawQueryVals() []string {
 	return b.RawValues
 }
 
-// Search represents filter for CVE substring search
-// ex. search=CVE-2022
+// FullTextSearchEnabled selects whether Search uses PostgreSQL full-text
+// search (tsvector/websearch_to_tsquery) or falls back to a plain LIKE scan.
+// Call DetectFullTextSearchSupport once at startup, from the app's DB-init
+// path, to set this from the configured DB dialect - only PostgreSQL has
+// the cve.search_tsv column and GIN index this relies on.
+var FullTextSearchEnabled = true
+
+// DetectFullTextSearchSupport sets FullTextSearchEnabled from db's dialect,
+// the same *gorm.DB the app's DB-init path already holds after gorm.Open.
+// Call it once at startup, before the app starts serving requests.
+func DetectFullTextSearchSupport(db *gorm.DB) {
+	FullTextSearchEnabled = db.Dialector.Name() == "postgres"
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// RelevanceSortColumn is the Sort column name controllers register for
+// "order by search relevance", only meaningful once a Search filter ran
+const RelevanceSortColumn = "relevance"
+
+const (
+	// SearchFilterArgs carries the active Search term, so Sort can build
+	// the ts_rank_cd ORDER BY expression for RelevanceSortColumn
+	SearchFilterArgs = "search_filter"
+)
+
+// Search represents filter for CVE search
+// ex. search=CVE-2022 or search="log4j" critical
 type Search struct {
 	RawFilter
 	value string
 }
 
-// ApplyQuery filters CVEs by their substring match name or description
+// ApplyQuery filters CVEs by full-text search over their name and
+// description in ExposedClustersSearch mode (a LIKE scan when
+// FullTextSearchEnabled is off), or by cluster UUID in CveSearch mode
 func (c *Search) ApplyQuery(tx *gorm.DB, args map[string]interface{}) error {
-	regex := fmt.Sprintf("%%%s%%", c.value)
-
 	switch args[SearchQuery] {
 	case ExposedClustersSearch:
+		if FullTextSearchEnabled {
+			tx.Where("cve.search_tsv @@ websearch_to_tsquery('english', ?)", c.value)
+			return nil
+		}
+		regex := fmt.Sprintf("%%%s%%", c.value)
 		tx.Where("cve.name LIKE ? OR cve.description LIKE ?", regex, regex)
 		return nil
 	case CveSearch:
-		tx.Where("cluster.uuid LIKE ?", regex)
+		if uuidPattern.MatchString(c.value) {
+			tx.Where("cluster.uuid = ?", c.value)
+			return nil
+		}
+		tx.Where("cluster.uuid ILIKE ?", fmt.Sprintf("%%%s%%", c.value))
 		return nil
 	}
 	return nil
 }
 
+// RelevanceOrder builds the ts_rank_cd ORDER BY expression Sort uses when
+// the caller asks to sort by RelevanceSortColumn. It is returned as a plain
+// string, rather than a bound clause.Expr, so Sort can pass it through
+// tx.Order and have it compose with other ORDER BY columns - gorm's
+// clause.OrderBy treats Expression and Columns as mutually exclusive, so an
+// Expression-based relevance order would silently replace any other
+// requested or default sort column instead of combining with it.
+func (c *Search) RelevanceOrder() string {
+	return fmt.Sprintf("ts_rank_cd(cve.search_tsv, websearch_to_tsquery('english', %s)) DESC", quoteSQLLiteral(c.value))
+}
+
+// quoteSQLLiteral escapes a value for safe inline use as a SQL string
+// literal, for the rare clauses (like ORDER BY) gorm won't let us bind
+// a placeholder into
+func quoteSQLLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
 // CvePublishDate represents filter for CVE publish date filtering
 // ex: publsihed=2021-01-01,2022-02-02
 type CvePublishDate struct {
@@ -129,25 +184,128 @@ func (c *CvssScore) ApplyQuery(tx *gorm.DB, _ map[string]interface{}) error {
 	return nil
 }
 
-// To be implemented
+const (
+	AccessibleClustersArgs = "accessible_clusters"
+	AccessibleImagesArgs   = "accessible_images"
+)
+
+// NumericThresholdOp is the comparison operator of a NumericThreshold
+type NumericThresholdOp string
+
+const (
+	ThresholdGte NumericThresholdOp = ">="
+	ThresholdGt  NumericThresholdOp = ">"
+	ThresholdLte NumericThresholdOp = "<="
+	ThresholdLt  NumericThresholdOp = "<"
+)
+
+// NumericThreshold represents a single `<op><value>` numeric comparison,
+// ex. affected_clusters=>=5
+type NumericThreshold struct {
+	Op    NumericThresholdOp
+	Value float64
+}
+
+// ParseNumericThreshold parses a raw `>=5`, `>5`, `<=5` or `<5` expression
+func ParseNumericThreshold(raw string) (*NumericThreshold, error) {
+	for _, op := range []NumericThresholdOp{ThresholdGte, ThresholdLte, ThresholdGt, ThresholdLt} {
+		if strings.HasPrefix(raw, string(op)) {
+			value, err := strconv.ParseFloat(strings.TrimPrefix(raw, string(op)), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid threshold value %q", raw)
+			}
+			return &NumericThreshold{Op: op, Value: value}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid threshold expression %q", raw)
+}
+
+// isGroupedQuery reports whether the query currently being built already
+// has a GROUP BY clause, so a threshold can be applied as a HAVING count
+// instead of a scalar subquery
+func isGroupedQuery(tx *gorm.DB) bool {
+	if tx.Statement == nil {
+		return false
+	}
+	_, grouped := tx.Statement.Clauses["GROUP BY"]
+	return grouped
+}
+
+// AffectingClusters represents filter for CVEs affecting one or more,
+// or none, of the caller's accessible clusters, with an optional minimum
+// affected cluster count
+// ex. affected_clusters=one_or_more,none  affected_clusters=>=5
 type AffectingClusters struct {
 	RawFilter
 	OneOrMore bool
 	None      bool
+	Threshold *NumericThreshold
 }
 
-func (a *AffectingClusters) ApplyQuery(tx *gorm.DB, _ map[string]interface{}) error {
+// ApplyQuery filters CVEs by whether they affect any of the caller's
+// accessible clusters, and optionally by a minimum affected cluster count
+func (a *AffectingClusters) ApplyQuery(tx *gorm.DB, args map[string]interface{}) error {
+	clusterIDs, _ := args[AccessibleClustersArgs].([]string)
+
+	switch {
+	case a.OneOrMore && a.None:
+		// both requested - no predicate, every CVE matches
+	case a.OneOrMore:
+		tx.Where("EXISTS (SELECT 1 FROM cluster_cve WHERE cluster_cve.cve_id = cve.id AND cluster_cve.cluster_id IN ?)", clusterIDs)
+	case a.None:
+		tx.Where("NOT EXISTS (SELECT 1 FROM cluster_cve WHERE cluster_cve.cve_id = cve.id AND cluster_cve.cluster_id IN ?)", clusterIDs)
+	}
+
+	if a.Threshold != nil {
+		if isGroupedQuery(tx) {
+			tx.Having(fmt.Sprintf(
+				"COUNT(DISTINCT CASE WHEN cluster_cve.cluster_id IN ? THEN cluster.id END) %s ?",
+				a.Threshold.Op), clusterIDs, a.Threshold.Value)
+		} else {
+			tx.Where(fmt.Sprintf(
+				"(SELECT COUNT(DISTINCT cluster_cve.cluster_id) FROM cluster_cve WHERE cluster_cve.cve_id = cve.id AND cluster_cve.cluster_id IN ?) %s ?",
+				a.Threshold.Op), clusterIDs, a.Threshold.Value)
+		}
+	}
 	return nil
 }
 
-// To be implemented
+// AffectingImages represents filter for CVEs affecting one or more,
+// or none, of the caller's accessible images, with an optional minimum
+// affected image count
+// ex. affected_images=one_or_more,none  affected_images=>=5
 type AffectingImages struct {
 	RawFilter
 	OneOrMore bool
 	None      bool
+	Threshold *NumericThreshold
 }
 
-func (a *AffectingImages) ApplyQuery(tx *gorm.DB, _ map[string]interface{}) error {
+// ApplyQuery filters CVEs by whether they affect any of the caller's
+// accessible images, and optionally by a minimum affected image count
+func (a *AffectingImages) ApplyQuery(tx *gorm.DB, args map[string]interface{}) error {
+	imageIDs, _ := args[AccessibleImagesArgs].([]string)
+
+	switch {
+	case a.OneOrMore && a.None:
+		// both requested - no predicate, every CVE matches
+	case a.OneOrMore:
+		tx.Where("EXISTS (SELECT 1 FROM image_cve WHERE image_cve.cve_id = cve.id AND image_cve.image_id IN ?)", imageIDs)
+	case a.None:
+		tx.Where("NOT EXISTS (SELECT 1 FROM image_cve WHERE image_cve.cve_id = cve.id AND image_cve.image_id IN ?)", imageIDs)
+	}
+
+	if a.Threshold != nil {
+		if isGroupedQuery(tx) {
+			tx.Having(fmt.Sprintf(
+				"COUNT(DISTINCT CASE WHEN image_cve.image_id IN ? THEN image.id END) %s ?",
+				a.Threshold.Op), imageIDs, a.Threshold.Value)
+		} else {
+			tx.Where(fmt.Sprintf(
+				"(SELECT COUNT(DISTINCT image_cve.image_id) FROM image_cve WHERE image_cve.cve_id = cve.id AND image_cve.image_id IN ?) %s ?",
+				a.Threshold.Op), imageIDs, a.Threshold.Value)
+		}
+	}
 	return nil
 }
 
@@ -211,6 +369,20 @@ func (s *Sort) ApplyQuery(tx *gorm.DB, args map[string]interface{}) error {
 		}
 		// Sort by user selected columns
 		for _, item := range s.Values {
+			if item.Column == RelevanceSortColumn {
+				if _, exists := sortArgs.SortableColumns[RelevanceSortColumn]; !exists {
+					return errors.New("invalid sort column selected")
+				}
+				if args[SearchQuery] != ExposedClustersSearch {
+					return errors.New("relevance sort requires an active search")
+				}
+				search, ok := args[SearchFilterArgs].(*Search)
+				if !ok {
+					return errors.New("relevance sort requires an active search")
+				}
+				tx.Order(search.RelevanceOrder())
+				continue
+			}
 			// Check if selected user column is mappable to sortable column sql expression
 			if col, exists := sortArgs.SortableColumns[item.Column]; exists {
 				if item.Desc {
@@ -253,6 +425,21 @@ func GetRequestedFilters(ctx *gin.Context) map[string]Filter {
 // ApplyFilters applies requested filters from query params on created query from controller,
 // filters needs to be allowed from controller in allowedFilters array
 func ApplyFilters(query *gorm.DB, allowedFilters []string, requestedFilters map[string]Filter, args map[string]interface{}) error {
+	allowed := func(name string) bool {
+		for _, f := range allowedFilters {
+			if f == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	if _, hasOffset := requestedFilters[OffsetQuery]; hasOffset && allowed(OffsetQuery) {
+		if _, hasCursor := requestedFilters[CursorQuery]; hasCursor && allowed(CursorQuery) {
+			return ErrOffsetCursorConflict
+		}
+	}
+
 	for _, allowedFilter := range allowedFilters {
 		if filter, requested := requestedFilters[allowedFilter]; requested {
 			err := filter.ApplyQuery(query, args)
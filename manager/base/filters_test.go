@@ -0,0 +1,148 @@
+package base
+
+import (
+	"fmt"
+	"testing"
+
+	"app/base/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormtests "gorm.io/gorm/utils/tests"
+)
+
+// newDryRunDB returns a *gorm.DB in DryRun mode against gorm's dummy
+// dialector, so ApplyQuery calls build their SQL without a live database
+func newDryRunDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(gormtests.DummyDialector{}, &gorm.Config{DryRun: true})
+	require.NoError(t, err)
+	return db.Table("cve")
+}
+
+func TestAffectingClustersOneOrMore(t *testing.T) {
+	tx := newDryRunDB(t)
+	filter := AffectingClusters{OneOrMore: true}
+	args := map[string]interface{}{AccessibleClustersArgs: []string{"cluster-1", "cluster-2"}}
+
+	require.NoError(t, filter.ApplyQuery(tx, args))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.Contains(t, sql, "EXISTS (SELECT 1 FROM cluster_cve")
+	assert.Contains(t, sql, "cluster_cve.cve_id = cve.id")
+}
+
+func TestAffectingClustersNone(t *testing.T) {
+	tx := newDryRunDB(t)
+	filter := AffectingClusters{None: true}
+	args := map[string]interface{}{AccessibleClustersArgs: []string{"cluster-1"}}
+
+	require.NoError(t, filter.ApplyQuery(tx, args))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.Contains(t, sql, "NOT EXISTS (SELECT 1 FROM cluster_cve")
+}
+
+func TestAffectingClustersOneOrMoreAndNoneIsNoop(t *testing.T) {
+	tx := newDryRunDB(t)
+	filter := AffectingClusters{OneOrMore: true, None: true}
+
+	require.NoError(t, filter.ApplyQuery(tx, map[string]interface{}{}))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.NotContains(t, sql, "EXISTS")
+}
+
+func TestAffectingClustersThreshold(t *testing.T) {
+	tx := newDryRunDB(t)
+	threshold, err := ParseNumericThreshold(">=5")
+	require.NoError(t, err)
+	filter := AffectingClusters{OneOrMore: true, Threshold: threshold}
+	args := map[string]interface{}{AccessibleClustersArgs: []string{"cluster-1"}}
+
+	require.NoError(t, filter.ApplyQuery(tx, args))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.Contains(t, sql, "SELECT COUNT(DISTINCT cluster_cve.cluster_id)")
+	assert.Contains(t, sql, ">= ?")
+}
+
+func TestAffectingClustersThresholdGrouped(t *testing.T) {
+	tx := newDryRunDB(t).Group("cve.id")
+	threshold, err := ParseNumericThreshold(">=5")
+	require.NoError(t, err)
+	filter := AffectingClusters{Threshold: threshold}
+	args := map[string]interface{}{AccessibleClustersArgs: []string{"cluster-1"}}
+
+	require.NoError(t, filter.ApplyQuery(tx, args))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.Contains(t, sql, "HAVING COUNT(DISTINCT CASE WHEN cluster_cve.cluster_id IN (?) THEN cluster.id END) >= ?")
+}
+
+func TestAffectingClustersThresholdGroupedScopesCountToAccessibleClusters(t *testing.T) {
+	tx := newDryRunDB(t).Group("cve.id")
+	threshold, err := ParseNumericThreshold(">=5")
+	require.NoError(t, err)
+	filter := AffectingClusters{Threshold: threshold}
+	args := map[string]interface{}{AccessibleClustersArgs: []string{"cluster-1", "cluster-2"}}
+
+	require.NoError(t, filter.ApplyQuery(tx, args))
+	stmt := tx.Find(&struct{}{}).Statement
+
+	// the HAVING count must only tally clusters the caller can access, not
+	// every cluster the CVE happens to be associated with
+	assert.Contains(t, fmt.Sprintf("%v", stmt.Vars), "cluster-1")
+	assert.Contains(t, fmt.Sprintf("%v", stmt.Vars), "cluster-2")
+}
+
+func TestAffectingImagesThresholdGroupedScopesCountToAccessibleImages(t *testing.T) {
+	tx := newDryRunDB(t).Group("cve.id")
+	threshold, err := ParseNumericThreshold(">=5")
+	require.NoError(t, err)
+	filter := AffectingImages{Threshold: threshold}
+	args := map[string]interface{}{AccessibleImagesArgs: []string{"image-1"}}
+
+	require.NoError(t, filter.ApplyQuery(tx, args))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.Contains(t, sql, "HAVING COUNT(DISTINCT CASE WHEN image_cve.image_id IN (?) THEN image.id END) >= ?")
+}
+
+func TestAffectingImagesOneOrMore(t *testing.T) {
+	tx := newDryRunDB(t)
+	filter := AffectingImages{OneOrMore: true}
+	args := map[string]interface{}{AccessibleImagesArgs: []string{"image-1"}}
+
+	require.NoError(t, filter.ApplyQuery(tx, args))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.Contains(t, sql, "EXISTS (SELECT 1 FROM image_cve")
+}
+
+func TestAffectingClustersComposesWithSeverityCvssScoreAndSort(t *testing.T) {
+	tx := newDryRunDB(t)
+
+	affecting := AffectingClusters{OneOrMore: true}
+	severity := Severity{Value: []models.Severity{models.Severity("critical")}}
+	cvss := CvssScore{From: 7.0, To: 10.0}
+	sort := Sort{Values: []SortItem{{Column: "cvss_score", Desc: true}}}
+	sortArgs := map[string]interface{}{
+		AccessibleClustersArgs: []string{"cluster-1"},
+		SortFilterArgs: SortArgs{
+			SortableColumns: map[string]string{"cvss_score": "COALESCE(cve.cvss3_score, cve.cvss2_score)"},
+		},
+	}
+
+	require.NoError(t, affecting.ApplyQuery(tx, sortArgs))
+	require.NoError(t, severity.ApplyQuery(tx, sortArgs))
+	require.NoError(t, cvss.ApplyQuery(tx, sortArgs))
+	require.NoError(t, sort.ApplyQuery(tx, sortArgs))
+
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.Contains(t, sql, "EXISTS (SELECT 1 FROM cluster_cve")
+	assert.Contains(t, sql, "cve.severity IN")
+	assert.Contains(t, sql, "COALESCE(cve.cvss3_score, cve.cvss2_score) >=")
+	assert.Contains(t, sql, "ORDER BY COALESCE(cve.cvss3_score, cve.cvss2_score) DESC NULLS LAST")
+}
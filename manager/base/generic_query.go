@@ -0,0 +1,214 @@
+package base
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"app/base/models"
+
+	"gorm.io/gorm"
+)
+
+const GenericQueryQuery = "q"
+
+const (
+	GenericQueryArgs = "generic_query"
+)
+
+// GenericQueryOp is the comparison operator requested for a single
+// field in the "q" parameter
+type GenericQueryOp string
+
+const (
+	GenericQueryOpEqual GenericQueryOp = "eq"
+	GenericQueryOpRange GenericQueryOp = "range"
+	GenericQueryOpIn    GenericQueryOp = "in"
+	GenericQueryOpLike  GenericQueryOp = "like"
+	GenericQueryOpGt    GenericQueryOp = "gt"
+	GenericQueryOpLt    GenericQueryOp = "lt"
+)
+
+// GenericQueryFieldType picks the value validator applied to a column
+// before its value(s) reach the generated SQL
+type GenericQueryFieldType string
+
+const (
+	GenericQueryFieldDate     GenericQueryFieldType = "date"
+	GenericQueryFieldFloat    GenericQueryFieldType = "float"
+	GenericQueryFieldSeverity GenericQueryFieldType = "severity"
+	GenericQueryFieldString   GenericQueryFieldType = "string"
+)
+
+// validSeverities mirrors the severities the flat Severity filter already
+// accepts, so q=severity=... is held to the same enum instead of silently
+// accepting (and never matching) an arbitrary string
+var validSeverities = map[models.Severity]bool{
+	models.Severity("none"):      true,
+	models.Severity("low"):       true,
+	models.Severity("moderate"):  true,
+	models.Severity("important"): true,
+	models.Severity("critical"):  true,
+}
+
+// GenericQueryColumn describes one column the "q" parameter is allowed
+// to target. Column is the SQL expression to filter on, Type selects the
+// value validator and AllowedOps restricts which operators are accepted
+// for that column.
+type GenericQueryColumn struct {
+	Column     string
+	Type       GenericQueryFieldType
+	AllowedOps []GenericQueryOp
+}
+
+// GenericQueryArgsValue is registered by controllers under
+// args[GenericQueryArgs], analogous to SortArgs for the Sort filter.
+// AllowedColumns maps the user-facing field name to its column definition.
+type GenericQueryArgsValue struct {
+	AllowedColumns map[string]GenericQueryColumn
+}
+
+// genericQueryCondition is one parsed "field<op>value(s)" triple
+type genericQueryCondition struct {
+	Field  string
+	Op     GenericQueryOp
+	Values []string
+}
+
+// GenericQuery implements the Harbor-style ad-hoc field filter
+// ex. q=severity=critical,cvss_score=[7.0~9.0],cluster_id={a b c},name=~log4j,cvss_score=>7
+type GenericQuery struct {
+	RawFilter
+}
+
+// ApplyQuery parses each comma-separated "field<op>value" condition from
+// RawValues against the controller-provided allow-list and attaches the
+// matching SQL predicate. Unknown columns, values that do not parse as the
+// column's Type and operators missing from the column's AllowedOps are all
+// rejected. Operators are checked against AllowedOps only, not against Type.
+func (q *GenericQuery) ApplyQuery(tx *gorm.DB, args map[string]interface{}) error {
+	argsValue, ok := args[GenericQueryArgs].(GenericQueryArgsValue)
+	if !ok {
+		return fmt.Errorf("generic query filter is not configured for this endpoint")
+	}
+
+	for _, raw := range q.RawValues {
+		cond, err := parseGenericQueryCondition(raw)
+		if err != nil {
+			return err
+		}
+
+		column, exists := argsValue.AllowedColumns[cond.Field]
+		if !exists {
+			return fmt.Errorf("unknown filter column %q", cond.Field)
+		}
+
+		if !genericQueryOpAllowed(column.AllowedOps, cond.Op) {
+			return fmt.Errorf("operator not permitted for column %q", cond.Field)
+		}
+
+		values, err := validateGenericQueryValues(column.Type, cond.Values)
+		if err != nil {
+			return fmt.Errorf("column %q: %w", cond.Field, err)
+		}
+
+		if err := applyGenericQueryCondition(tx, column.Column, cond.Op, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func genericQueryOpAllowed(allowed []GenericQueryOp, op GenericQueryOp) bool {
+	for _, a := range allowed {
+		if a == op {
+			return true
+		}
+	}
+	return false
+}
+
+// parseGenericQueryCondition parses a single "field=value", "field=[a~b]",
+// "field={a b c}", "field=~substr", "field=>v" or "field=<v" expression
+func parseGenericQueryCondition(raw string) (genericQueryCondition, error) {
+	field, expr, found := strings.Cut(raw, "=")
+	if !found || field == "" {
+		return genericQueryCondition{}, fmt.Errorf("invalid filter expression %q", raw)
+	}
+
+	switch {
+	case strings.HasPrefix(expr, "[") && strings.HasSuffix(expr, "]"):
+		bounds := strings.SplitN(strings.Trim(expr, "[]"), "~", 2)
+		if len(bounds) != 2 {
+			return genericQueryCondition{}, fmt.Errorf("invalid range expression %q", raw)
+		}
+		return genericQueryCondition{Field: field, Op: GenericQueryOpRange, Values: bounds}, nil
+	case strings.HasPrefix(expr, "{") && strings.HasSuffix(expr, "}"):
+		list := strings.Fields(strings.Trim(expr, "{}"))
+		return genericQueryCondition{Field: field, Op: GenericQueryOpIn, Values: list}, nil
+	case strings.HasPrefix(expr, "~"):
+		return genericQueryCondition{Field: field, Op: GenericQueryOpLike, Values: []string{strings.TrimPrefix(expr, "~")}}, nil
+	case strings.HasPrefix(expr, ">"):
+		return genericQueryCondition{Field: field, Op: GenericQueryOpGt, Values: []string{strings.TrimPrefix(expr, ">")}}, nil
+	case strings.HasPrefix(expr, "<"):
+		return genericQueryCondition{Field: field, Op: GenericQueryOpLt, Values: []string{strings.TrimPrefix(expr, "<")}}, nil
+	default:
+		return genericQueryCondition{Field: field, Op: GenericQueryOpEqual, Values: []string{expr}}, nil
+	}
+}
+
+// validateGenericQueryValues checks every raw value against the column's
+// declared type and returns them converted to the type gorm should bind
+func validateGenericQueryValues(fieldType GenericQueryFieldType, raw []string) ([]interface{}, error) {
+	values := make([]interface{}, 0, len(raw))
+	for _, v := range raw {
+		switch fieldType {
+		case GenericQueryFieldDate:
+			t, err := time.Parse(DateFormat, v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid date %q", v)
+			}
+			values = append(values, t)
+		case GenericQueryFieldFloat:
+			f, err := strconv.ParseFloat(v, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", v)
+			}
+			values = append(values, f)
+		case GenericQueryFieldSeverity:
+			severity := models.Severity(v)
+			if !validSeverities[severity] {
+				return nil, fmt.Errorf("invalid severity %q", v)
+			}
+			values = append(values, severity)
+		case GenericQueryFieldString:
+			values = append(values, v)
+		default:
+			return nil, fmt.Errorf("unsupported field type %q", fieldType)
+		}
+	}
+	return values, nil
+}
+
+// applyGenericQueryCondition attaches the gorm predicate matching a
+// single parsed condition to tx
+func applyGenericQueryCondition(tx *gorm.DB, column string, op GenericQueryOp, values []interface{}) error {
+	switch op {
+	case GenericQueryOpEqual:
+		tx.Where(fmt.Sprintf("%s = ?", column), values[0])
+	case GenericQueryOpRange:
+		tx.Where(fmt.Sprintf("%s >= ? AND %s <= ?", column, column), values[0], values[1])
+	case GenericQueryOpIn:
+		tx.Where(fmt.Sprintf("%s IN ?", column), values)
+	case GenericQueryOpLike:
+		tx.Where(fmt.Sprintf("%s ILIKE ?", column), fmt.Sprintf("%%%v%%", values[0]))
+	case GenericQueryOpGt:
+		tx.Where(fmt.Sprintf("%s > ?", column), values[0])
+	case GenericQueryOpLt:
+		tx.Where(fmt.Sprintf("%s < ?", column), values[0])
+	default:
+		return fmt.Errorf("unsupported operator %q", op)
+	}
+	return nil
+}
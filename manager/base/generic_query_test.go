@@ -0,0 +1,151 @@
+package base
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func genericQueryArgsForTest() map[string]interface{} {
+	return map[string]interface{}{
+		GenericQueryArgs: GenericQueryArgsValue{
+			AllowedColumns: map[string]GenericQueryColumn{
+				"severity": {
+					Column:     "cve.severity",
+					Type:       GenericQueryFieldSeverity,
+					AllowedOps: []GenericQueryOp{GenericQueryOpEqual, GenericQueryOpIn},
+				},
+				"cvss_score": {
+					Column:     "COALESCE(cve.cvss3_score, cve.cvss2_score)",
+					Type:       GenericQueryFieldFloat,
+					AllowedOps: []GenericQueryOp{GenericQueryOpRange, GenericQueryOpGt, GenericQueryOpLt},
+				},
+				"published": {
+					Column:     "cve.public_date",
+					Type:       GenericQueryFieldDate,
+					AllowedOps: []GenericQueryOp{GenericQueryOpGt, GenericQueryOpLt},
+				},
+				"name": {
+					Column:     "cve.name",
+					Type:       GenericQueryFieldString,
+					AllowedOps: []GenericQueryOp{GenericQueryOpLike, GenericQueryOpEqual},
+				},
+			},
+		},
+	}
+}
+
+func TestGenericQueryEqualCondition(t *testing.T) {
+	tx := newDryRunDB(t)
+	filter := GenericQuery{RawFilter: RawFilter{RawValues: []string{"severity=critical"}}}
+
+	require.NoError(t, filter.ApplyQuery(tx, genericQueryArgsForTest()))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.Contains(t, sql, "cve.severity = ?")
+}
+
+func TestGenericQueryRangeCondition(t *testing.T) {
+	tx := newDryRunDB(t)
+	filter := GenericQuery{RawFilter: RawFilter{RawValues: []string{"cvss_score=[7.0~9.0]"}}}
+
+	require.NoError(t, filter.ApplyQuery(tx, genericQueryArgsForTest()))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.Contains(t, sql, "COALESCE(cve.cvss3_score, cve.cvss2_score) >= ? AND COALESCE(cve.cvss3_score, cve.cvss2_score) <= ?")
+}
+
+func TestGenericQueryInCondition(t *testing.T) {
+	tx := newDryRunDB(t)
+	filter := GenericQuery{RawFilter: RawFilter{RawValues: []string{"severity={critical important}"}}}
+
+	require.NoError(t, filter.ApplyQuery(tx, genericQueryArgsForTest()))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.Contains(t, sql, "cve.severity IN")
+}
+
+func TestGenericQueryLikeCondition(t *testing.T) {
+	tx := newDryRunDB(t)
+	filter := GenericQuery{RawFilter: RawFilter{RawValues: []string{"name=~log4j"}}}
+
+	require.NoError(t, filter.ApplyQuery(tx, genericQueryArgsForTest()))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.Contains(t, sql, "cve.name ILIKE ?")
+}
+
+func TestGenericQueryGtLtCondition(t *testing.T) {
+	tx := newDryRunDB(t)
+	filter := GenericQuery{RawFilter: RawFilter{RawValues: []string{"cvss_score=>7", "published=<2022-01-01"}}}
+
+	require.NoError(t, filter.ApplyQuery(tx, genericQueryArgsForTest()))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.Contains(t, sql, "COALESCE(cve.cvss3_score, cve.cvss2_score) > ?")
+	assert.Contains(t, sql, "cve.public_date < ?")
+}
+
+func TestGenericQueryRejectsUnknownColumn(t *testing.T) {
+	tx := newDryRunDB(t)
+	filter := GenericQuery{RawFilter: RawFilter{RawValues: []string{"bogus=1"}}}
+
+	err := filter.ApplyQuery(tx, genericQueryArgsForTest())
+	assert.Error(t, err)
+}
+
+func TestGenericQueryRejectsDisallowedOperator(t *testing.T) {
+	tx := newDryRunDB(t)
+	filter := GenericQuery{RawFilter: RawFilter{RawValues: []string{"severity=~crit"}}}
+
+	err := filter.ApplyQuery(tx, genericQueryArgsForTest())
+	assert.Error(t, err)
+}
+
+func TestGenericQueryRejectsUnparseableExpression(t *testing.T) {
+	tx := newDryRunDB(t)
+	filter := GenericQuery{RawFilter: RawFilter{RawValues: []string{"nocolon"}}}
+
+	err := filter.ApplyQuery(tx, genericQueryArgsForTest())
+	assert.Error(t, err)
+}
+
+func TestGenericQueryValidatesDate(t *testing.T) {
+	tx := newDryRunDB(t)
+	filter := GenericQuery{RawFilter: RawFilter{RawValues: []string{"published=>not-a-date"}}}
+
+	err := filter.ApplyQuery(tx, genericQueryArgsForTest())
+	assert.Error(t, err)
+}
+
+func TestGenericQueryValidatesFloat(t *testing.T) {
+	tx := newDryRunDB(t)
+	filter := GenericQuery{RawFilter: RawFilter{RawValues: []string{"cvss_score=>not-a-number"}}}
+
+	err := filter.ApplyQuery(tx, genericQueryArgsForTest())
+	assert.Error(t, err)
+}
+
+func TestGenericQueryRejectsUnknownSeverity(t *testing.T) {
+	tx := newDryRunDB(t)
+	filter := GenericQuery{RawFilter: RawFilter{RawValues: []string{"severity=bogus"}}}
+
+	err := filter.ApplyQuery(tx, genericQueryArgsForTest())
+	assert.Error(t, err)
+}
+
+func TestGenericQueryAcceptsKnownSeverities(t *testing.T) {
+	tx := newDryRunDB(t)
+	filter := GenericQuery{RawFilter: RawFilter{RawValues: []string{"severity=important"}}}
+
+	assert.NoError(t, filter.ApplyQuery(tx, genericQueryArgsForTest()))
+}
+
+func TestGenericQueryRejectsWhenNotConfigured(t *testing.T) {
+	tx := newDryRunDB(t)
+	filter := GenericQuery{RawFilter: RawFilter{RawValues: []string{"severity=critical"}}}
+
+	err := filter.ApplyQuery(tx, map[string]interface{}{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,165 @@
+package base
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormtests "gorm.io/gorm/utils/tests"
+)
+
+// namedDialector wraps DummyDialector to report an arbitrary dialect name,
+// so DetectFullTextSearchSupport can be tested without a real postgres driver
+type namedDialector struct {
+	gormtests.DummyDialector
+	name string
+}
+
+func (d namedDialector) Name() string {
+	return d.name
+}
+
+func TestDetectFullTextSearchSupportEnablesOnPostgres(t *testing.T) {
+	FullTextSearchEnabled = false
+	defer func() { FullTextSearchEnabled = true }()
+
+	db, err := gorm.Open(namedDialector{name: "postgres"}, &gorm.Config{DryRun: true})
+	require.NoError(t, err)
+
+	DetectFullTextSearchSupport(db)
+	assert.True(t, FullTextSearchEnabled)
+}
+
+func TestDetectFullTextSearchSupportDisablesOnOtherDialects(t *testing.T) {
+	FullTextSearchEnabled = true
+	defer func() { FullTextSearchEnabled = true }()
+
+	db, err := gorm.Open(namedDialector{name: "sqlite"}, &gorm.Config{DryRun: true})
+	require.NoError(t, err)
+
+	DetectFullTextSearchSupport(db)
+	assert.False(t, FullTextSearchEnabled)
+}
+
+func TestSearchExposedClustersUsesFullTextSearch(t *testing.T) {
+	tx := newDryRunDB(t)
+	search := Search{value: "log4j"}
+
+	require.NoError(t, search.ApplyQuery(tx, map[string]interface{}{SearchQuery: ExposedClustersSearch}))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.Contains(t, sql, "cve.search_tsv @@ websearch_to_tsquery('english', ?)")
+}
+
+func TestSearchExposedClustersFallsBackToLike(t *testing.T) {
+	FullTextSearchEnabled = false
+	defer func() { FullTextSearchEnabled = true }()
+
+	tx := newDryRunDB(t)
+	search := Search{value: "log4j"}
+
+	require.NoError(t, search.ApplyQuery(tx, map[string]interface{}{SearchQuery: ExposedClustersSearch}))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.Contains(t, sql, "cve.name LIKE ? OR cve.description LIKE ?")
+}
+
+func TestSearchCveAnchorsFullUUID(t *testing.T) {
+	tx := newDryRunDB(t)
+	search := Search{value: "3fa85f64-5717-4562-b3fc-2c963f66afa6"}
+
+	require.NoError(t, search.ApplyQuery(tx, map[string]interface{}{SearchQuery: CveSearch}))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.Contains(t, sql, "cluster.uuid = ?")
+}
+
+func TestSearchCveSubstringMatchWhenNotFullUUID(t *testing.T) {
+	tx := newDryRunDB(t)
+	search := Search{value: "3fa85f64"}
+
+	require.NoError(t, search.ApplyQuery(tx, map[string]interface{}{SearchQuery: CveSearch}))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.Contains(t, sql, "cluster.uuid ILIKE ?")
+}
+
+func relevanceSortableArgs() map[string]interface{} {
+	return map[string]interface{}{
+		SearchQuery: ExposedClustersSearch,
+		SortFilterArgs: SortArgs{
+			SortableColumns: map[string]string{RelevanceSortColumn: "relevance"},
+		},
+	}
+}
+
+func TestSortRelevanceUsesTsRank(t *testing.T) {
+	tx := newDryRunDB(t)
+	search := &Search{value: "log4j"}
+	sort := Sort{Values: []SortItem{{Column: RelevanceSortColumn}}}
+
+	args := relevanceSortableArgs()
+	args[SearchFilterArgs] = search
+	require.NoError(t, sort.ApplyQuery(tx, args))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.Contains(t, sql, "ORDER BY ts_rank_cd(cve.search_tsv, websearch_to_tsquery('english', 'log4j')) DESC")
+}
+
+func TestSortRelevanceComposesWithOtherSortColumns(t *testing.T) {
+	tx := newDryRunDB(t)
+	search := &Search{value: "log4j"}
+	sort := Sort{Values: []SortItem{{Column: RelevanceSortColumn}, {Column: "severity", Desc: true}}}
+
+	args := map[string]interface{}{
+		SearchQuery:      ExposedClustersSearch,
+		SearchFilterArgs: search,
+		SortFilterArgs: SortArgs{
+			SortableColumns: map[string]string{RelevanceSortColumn: "relevance", "severity": "cve.severity"},
+		},
+	}
+	require.NoError(t, sort.ApplyQuery(tx, args))
+	sql := tx.Find(&struct{}{}).Statement.SQL.String()
+
+	assert.Contains(t, sql, "ts_rank_cd(cve.search_tsv, websearch_to_tsquery('english', 'log4j')) DESC")
+	assert.Contains(t, sql, "cve.severity DESC NULLS LAST")
+}
+
+func TestSortRelevanceWithoutSearchErrors(t *testing.T) {
+	tx := newDryRunDB(t)
+	sort := Sort{Values: []SortItem{{Column: RelevanceSortColumn}}}
+
+	err := sort.ApplyQuery(tx, relevanceSortableArgs())
+	assert.Error(t, err)
+}
+
+func TestSortRelevanceNotInSortableColumnsErrors(t *testing.T) {
+	tx := newDryRunDB(t)
+	search := &Search{value: "log4j"}
+	sort := Sort{Values: []SortItem{{Column: RelevanceSortColumn}}}
+
+	args := map[string]interface{}{
+		SearchQuery:      ExposedClustersSearch,
+		SearchFilterArgs: search,
+		SortFilterArgs:   SortArgs{},
+	}
+	err := sort.ApplyQuery(tx, args)
+	assert.Error(t, err)
+}
+
+func TestSortRelevanceWrongSearchModeErrors(t *testing.T) {
+	tx := newDryRunDB(t)
+	search := &Search{value: "3fa85f64-5717-4562-b3fc-2c963f66afa6"}
+	sort := Sort{Values: []SortItem{{Column: RelevanceSortColumn}}}
+
+	args := map[string]interface{}{
+		SearchQuery:      CveSearch,
+		SearchFilterArgs: search,
+		SortFilterArgs: SortArgs{
+			SortableColumns: map[string]string{RelevanceSortColumn: "relevance"},
+		},
+	}
+	err := sort.ApplyQuery(tx, args)
+	assert.Error(t, err)
+}